@@ -0,0 +1,52 @@
+package audio
+
+import "math"
+
+// Per-channel resonant multi-mode filter (a Chamberlin state-variable
+// filter), modeled on the lp/hp/bp/br filters in the Csound Air module.
+// Coefficients are recomputed once a tick by Mixer.tick (see filterF/
+// filterQ); svf itself runs at audio rate from startPair's wave
+// closure, 2x-oversampled to stay stable as cutoff approaches Nyquist.
+
+const ( // Channel.FilterMode
+	FiltOff = iota
+	FiltLP
+	FiltHP
+	FiltBP
+	FiltBR // Notch
+)
+
+// svf runs one sample of in through c's state-variable filter and
+// returns the output selected by c.FilterMode.
+func (c *Channel) svf(in int32) int32 {
+	f := c.filterF / 2 // Half per substep; two substeps = 2x oversample
+	low, band := float64(c.fLow), float64(c.fBand)
+	var high float64
+	for step := 0; step < 2; step++ {
+		low += f * band
+		high = float64(in) - low - c.filterQ*band
+		band += f * high
+	}
+	c.fLow = clamp32(low)
+	c.fBand = clamp32(band)
+
+	switch c.FilterMode {
+	case FiltHP:
+		return clamp32(high)
+	case FiltBP:
+		return clamp32(band)
+	case FiltBR:
+		return clamp32(high + low)
+	default: // FiltLP
+		return clamp32(low)
+	}
+}
+
+func clamp32(f float64) int32 {
+	if f > math.MaxInt32 {
+		return math.MaxInt32
+	} else if f < math.MinInt32 {
+		return math.MinInt32
+	}
+	return int32(f)
+}
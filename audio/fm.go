@@ -0,0 +1,155 @@
+package audio
+
+// Multi-operator FM voices, modeled on the 4-op algorithms of OPL2/OPL3
+// style FM chips. An Operator is a single phase/envelope generator; a
+// Voice strings up to four of them together according to an Algorithm,
+// which picks the routing graph between them and which outputs are
+// summed as the audible "carrier" signal.
+//
+// A Voice rides on top of a channel pair the same way PairPM/PairAM do:
+// selecting PairFM on the left channel of a pair switches startPair over
+// to evaluating the pair's Voice instead of Ch[i*2]/[i*2+1] directly.
+
+type Operator struct {
+	Wave       int // Index of wave to use for wave function
+	Len, Phase uint32
+	period     uint32
+
+	Note int32 // Midi note number, same encoding as Channel.Note
+
+	Vol                                   int32 // Current envelope level (Q16)
+	Attack, Peak, Decay, Sustain, Release int32
+	EnvPos                                int32
+	NoteOn                                bool
+
+	OutLevel int32 // Output scaling applied after the wave lookup (Q16)
+	Feedback int32 // 0..7; self-feedback shift amount, op1 only, as in OPL
+
+	out    int32    // Last output, fed into the next operator's phase
+	fbHist [2]int32 // Last two outputs of this op, for the feedback average
+}
+
+type Algorithm int
+
+const (
+	Alg4Serial    Algorithm = iota // 1->2->3->4, op4 is the carrier
+	Alg2ParPM                      // (1->2) + (3->4), op2 and op4 are carriers
+	AlgFB1Serial                   // feedback on op1, then 1->2->3->4 serial
+	Alg1PlusChain                  // op1 is its own carrier, plus 2->3->4 chain, summed
+)
+
+// A Voice is the 4-operator FM unit that rides on a channel pair when
+// that pair's PairMode is PairFM.
+type Voice struct {
+	Op        [4]Operator
+	Algorithm Algorithm
+}
+
+// advance runs one sample's worth of phase modulation algebra for op,
+// adding the (already Q16-scaled) output of the previous operator in the
+// chain to its phase argument, much as PairPM feeds the left osc's
+// output into the right osc's phase.
+func (op *Operator) advance(m *Mixer, modIn int32) int32 {
+	op.Phase = (op.Phase + op.period) % op.Len
+	phase := uint32(int32(op.Phase) + modIn)
+	dry := int32(m.wave(op.Wave, phase)) * op.Vol >> 16
+	op.out = dry * op.OutLevel >> 16
+	return op.out
+}
+
+// feedback computes op1's self-modulation term: the average of its last
+// two outputs, shifted by op.Feedback (0..7, 0 meaning no feedback).
+func (op *Operator) feedback() int32 {
+	if op.Feedback == 0 {
+		return 0
+	}
+	avg := (op.fbHist[0] + op.fbHist[1]) / 2
+	return avg >> (8 - uint(op.Feedback))
+}
+
+func (op *Operator) pushFeedback() {
+	op.fbHist[0] = op.fbHist[1]
+	op.fbHist[1] = op.out
+}
+
+// evalVoice runs one sample through v according to its Algorithm and
+// returns the mixed carrier output.
+func evalVoice(m *Mixer, v *Voice) int32 {
+	op := &v.Op
+	switch v.Algorithm {
+	case Alg2ParPM:
+		o1 := op[0].advance(m, 0)
+		o2 := op[1].advance(m, o1)
+		o3 := op[2].advance(m, 0)
+		o4 := op[3].advance(m, o3)
+		return o2 + o4
+	case AlgFB1Serial:
+		fb := op[0].feedback()
+		o1 := op[0].advance(m, fb)
+		op[0].pushFeedback()
+		o2 := op[1].advance(m, o1)
+		o3 := op[2].advance(m, o2)
+		o4 := op[3].advance(m, o3)
+		return o4
+	case Alg1PlusChain:
+		o1 := op[0].advance(m, 0)
+		o2 := op[1].advance(m, 0)
+		o3 := op[2].advance(m, o2)
+		o4 := op[3].advance(m, o3)
+		return o1 + o4
+	default: // Alg4Serial
+		o1 := op[0].advance(m, 0)
+		o2 := op[1].advance(m, o1)
+		o3 := op[2].advance(m, o2)
+		o4 := op[3].advance(m, o3)
+		return o4
+	}
+}
+
+// tickVoice runs the per-tick envelope and pitch update for every
+// operator in v, mirroring the envelope switch and pitch line in
+// Mixer.tick.
+func tickVoice(m *Mixer, v *Voice) {
+	for i := range v.Op {
+		op := &v.Op[i]
+		// This avoids division by 0, same as Channel.FilterLen;
+		// NewMixerN sets Len, but an Operator whose Len was zeroed
+		// later (e.g. through OpOnPair) would otherwise make the
+		// modulo in advance panic.
+		if op.Len == 0 {
+			op.Len = 0x10000
+		}
+		op.period = uint32(float64(op.Len/m.srate) * Note(op.Note))
+		switch {
+		case op.Release < 1:
+		case !op.NoteOn:
+			op.Vol = max(0, op.Vol-op.Release)
+		case op.EnvPos < op.Peak/op.Attack:
+			op.Vol = min(0x10000, op.Vol+op.Attack)
+		case op.Vol > op.Sustain:
+			op.Vol = max(0, op.Vol-op.Decay)
+		}
+		op.EnvPos++
+	}
+}
+
+// SetAlgorithm configures the FM voice riding on the given pair: which
+// routing graph to use and how much self-feedback op1 gets (0..7).
+// Only AlgFB1Serial applies the feedback; the other algorithms ignore
+// it.
+func (m *Mixer) SetAlgorithm(voice int, alg int, fb int) {
+	if fb < 0 {
+		fb = 0
+	} else if fb > 7 {
+		fb = 7
+	}
+	v := &m.Voices[voice]
+	v.Algorithm = Algorithm(alg)
+	v.Op[0].Feedback = int32(fb)
+}
+
+// OpOnPair runs op against operator opIdx (0..3) of the voice riding on
+// the given pair.
+func (m *Mixer) OpOnPair(pair int, opIdx int, op func(*Operator)) {
+	op(&m.Voices[pair].Op[opIdx])
+}
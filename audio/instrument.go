@@ -0,0 +1,122 @@
+package audio
+
+import "encoding/json"
+
+// Instrument snapshots every synthesis parameter the sequencer would
+// normally poke field-by-field: wave, envelope, tremolo/vibrato, delay,
+// filter and shaper settings. PairInstrument bundles one Instrument per
+// side of a pair plus the pairing mode, so a whole voice can be
+// captured or restored in one call instead of hand-tuning every field
+// per song.
+type Instrument struct {
+	Wave int
+
+	Attack, Peak, Decay, Sustain, Release int32
+
+	Tremolo     int32
+	TremoloWave int
+	TremoloRate uint32
+
+	Vibrato     int32
+	VibratoWave int
+	VibratoRate uint32
+
+	DelayTicks uint32
+	DryLevel   int32
+	WetLevel   int32
+	Feedback   int32
+	FilterLen  uint16
+
+	FilterMode int
+	FilterCut  int32
+	FilterRes  int32
+	FilterEnv  int32
+
+	Shape      int
+	Drive      int32
+	ShapeMix   int32
+	ShapeTable []int16
+}
+
+// PairInstrument is a full L/R patch, as applied to one channel pair.
+type PairInstrument struct {
+	PairMode    int
+	Left, Right Instrument
+}
+
+// ApplyInstrument queues ins to be written into m.Ch[pair*2:pair*2+2]
+// at the next tick boundary (the same place DelayNote/DelayTicks get
+// consumed), so a patch change never tears mid-sample.
+func (m *Mixer) ApplyInstrument(pair int, ins *PairInstrument) {
+	m.pending[pair] = ins
+}
+
+// CaptureInstrument snapshots the current patch riding on the given
+// pair. The delay length is not captured: DelayTicks comes back 0,
+// which ApplyInstrument treats as leaving the delay unchanged.
+func (m *Mixer) CaptureInstrument(pair int) *PairInstrument {
+	return &PairInstrument{
+		PairMode: m.Ch[pair*2].PairMode,
+		Left:     captureChannel(&m.Ch[pair*2]),
+		Right:    captureChannel(&m.Ch[pair*2+1]),
+	}
+}
+
+func captureChannel(c *Channel) Instrument {
+	return Instrument{
+		Wave:        c.Wave,
+		Attack:      c.Attack,
+		Peak:        c.Peak,
+		Decay:       c.Decay,
+		Sustain:     c.Sustain,
+		Release:     c.Release,
+		Tremolo:     c.Tremolo,
+		TremoloWave: c.TremoloWave,
+		TremoloRate: c.TremoloRate,
+		Vibrato:     c.Vibrato,
+		VibratoWave: c.VibratoWave,
+		VibratoRate: c.VibratoRate,
+		DryLevel:    c.DryLevel,
+		WetLevel:    c.WetLevel,
+		Feedback:    c.Feedback,
+		FilterLen:   c.FilterLen,
+		FilterMode:  c.FilterMode,
+		FilterCut:   c.FilterCut,
+		FilterRes:   c.FilterRes,
+		FilterEnv:   c.FilterEnv,
+		Shape:       c.Shape,
+		Drive:       c.Drive,
+		ShapeMix:    c.ShapeMix,
+		ShapeTable:  c.ShapeTable,
+	}
+}
+
+// applyChannel writes ins into c. Called from Mixer.tick once the patch
+// change's tick boundary has arrived.
+func applyChannel(c *Channel, ins *Instrument) {
+	c.Wave = ins.Wave
+	c.Attack, c.Peak, c.Decay, c.Sustain, c.Release = ins.Attack, ins.Peak, ins.Decay, ins.Sustain, ins.Release
+	c.Tremolo, c.TremoloWave, c.TremoloRate = ins.Tremolo, ins.TremoloWave, ins.TremoloRate
+	c.Vibrato, c.VibratoWave, c.VibratoRate = ins.Vibrato, ins.VibratoWave, ins.VibratoRate
+	if ins.DelayTicks > 0 {
+		c.DelayTicks = ins.DelayTicks
+	}
+	c.DryLevel, c.WetLevel, c.Feedback, c.FilterLen = ins.DryLevel, ins.WetLevel, ins.Feedback, ins.FilterLen
+	c.FilterMode, c.FilterCut, c.FilterRes, c.FilterEnv = ins.FilterMode, ins.FilterCut, ins.FilterRes, ins.FilterEnv
+	c.Shape, c.Drive, c.ShapeMix, c.ShapeTable = ins.Shape, ins.Drive, ins.ShapeMix, ins.ShapeTable
+}
+
+// MarshalInstrument encodes ins as indented JSON.
+func MarshalInstrument(ins *PairInstrument) ([]byte, error) {
+	return json.MarshalIndent(ins, "", "  ")
+}
+
+// ParseInstrument decodes a PairInstrument previously produced by
+// MarshalInstrument.
+func ParseInstrument(data []byte) (*PairInstrument, error) {
+	var ins PairInstrument
+	if err := json.Unmarshal(data, &ins); err != nil {
+		return nil, err
+	}
+	return &ins, nil
+}
@@ -20,7 +20,9 @@ package audio
 
 import "math"
 
-// The number of channel pairs, or mixer chans
+// The default number of channel pairs for NewMixer. Real polyphony
+// (more voices than this) goes through NewMixerN and a VoiceManager
+// instead of raising this constant.
 const NumChans int = 1
 
 type Mixer struct {
@@ -31,12 +33,14 @@ type Mixer struct {
 	count    uint32 // Point counter
 	nextTick uint32 // Location of next tick in points
 
-	Ch        *[NumChans * 2]Channel  // Channels; pairs next to each other
-	chans     *[NumChans](chan int32) // Data back from channel pairs
-	BPM       uint32                  // Song speed in beats per minute
-	TickRate  uint32                  // Ticks per update
-	TickSpeed uint32                  // Callback after this many ticks
-	tickCount uint32                  // Counts down ticks until callback
+	Ch        []Channel         // Channels; pairs next to each other
+	chans     []chan int32      // Data back from channel pairs
+	Voices    []Voice           // FM voice riding each pair, for PairFM
+	pending   []*PairInstrument // Patches awaiting the next tick boundary
+	BPM       uint32            // Song speed in beats per minute
+	TickRate  uint32            // Ticks per update
+	TickSpeed uint32            // Callback after this many ticks
+	tickCount uint32            // Counts down ticks until callback
 }
 
 const ( // Channel pairing modes
@@ -44,6 +48,7 @@ const ( // Channel pairing modes
 	PairPM            // Phase modulation
 	PairAM            // Amplitude modulation
 	PairSync          // Phase of left osc overflow = reset phase of right
+	PairFM            // 4-operator FM voice; see Voice/Algorithm
 )
 
 // The only reason that some of these are hidden is because they're
@@ -91,14 +96,39 @@ type Channel struct {
 	VibratoWave  int
 	VibratoRate  uint32
 	VibratoPhase uint32
+
+	FilterMode int   // FiltOff, FiltLP, FiltHP, FiltBP or FiltBR
+	FilterCut  int32 // Cutoff, midi-note-style like Note, so Note() gives Hz
+	FilterRes  int32 // Resonance, 0..0x10000, mapped to Q=0.5..20
+	FilterEnv  int32 // Depth that the amplitude envelope adds to FilterCut
+	filterF    float64
+	filterQ    float64
+	fLow       int32 // SVF state: lowpass accumulator
+	fBand      int32 // SVF state: bandpass accumulator
+
+	Shape      int     // ShapeOff, ShapeSoftTanh, ShapeHardClip, ShapeFoldback, ShapeBitcrush or ShapeLookup
+	Drive      int32   // Pre-gain into the shaper (Q16)
+	ShapeMix   int32   // Dry/wet crossfade for the shaped signal (Q16)
+	ShapeTable []int16 // Curve used by ShapeLookup; 256 or 1024 samples, caller-supplied
 }
 
+// NewMixer builds a Mixer with the default NumChans channel pairs. For
+// real polyphony, use NewMixerN with a VoiceManager instead.
 func NewMixer(wave func(int, uint32) int16, seq func(*Mixer)) Mixer {
+	return NewMixerN(wave, seq, NumChans)
+}
+
+// NewMixerN builds a Mixer with nPairs channel pairs (2*nPairs
+// channels), so a VoiceManager can allocate polyphony independently of
+// any compile-time channel count.
+func NewMixerN(wave func(int, uint32) int16, seq func(*Mixer), nPairs int) Mixer {
 	m := Mixer{
 		wave:      wave,
 		seq:       seq,
-		Ch:        new([NumChans * 2]Channel),
-		chans:     new([NumChans]chan int32),
+		Ch:        make([]Channel, nPairs*2),
+		chans:     make([]chan int32, nPairs),
+		Voices:    make([]Voice, nPairs),
+		pending:   make([]*PairInstrument, nPairs),
 		BPM:       120,
 		TickRate:  24,
 		TickSpeed: 6,
@@ -111,20 +141,33 @@ func NewMixer(wave func(int, uint32) int16, seq func(*Mixer)) Mixer {
 		c.Len = 0x10000
 		c.DryLevel = 0x10000
 	}
+	for i := range m.Voices {
+		for j := range m.Voices[i].Op {
+			op := &m.Voices[i].Op[j]
+			op.Len = 0x10000
+			op.OutLevel = 0x10000
+		}
+	}
 	return m
 }
 
 func (m *Mixer) Start(output chan int16, srate uint32) {
 	m.srate = srate
 
+	// Go is known to hang for up to 4ms at absolute most. This would
+	// put the ideal GC amount at 48*4 = 192, and because of stereo,
+	// that's actually 384. This was at 128 before, and was still
+	// underrunning. It's important to notice this in addition to the
+	// SDL audio buffer. As more pairs are added that budget is split
+	// between them rather than kept flat per pair, down to a floor of
+	// 48 per pair, so total buffered memory stays at 384 up to 8 pairs
+	// and grows by 48 per pair beyond that.
+	bufSize := 384 / len(m.chans)
+	if bufSize < 48 {
+		bufSize = 48
+	}
 	for i := range m.chans {
-		// Go is known to hang for up to 4ms at absolute most.
-		// This would put my ideal GC amount at 48*4 = 192 And
-		// because of stereo, that's actually 384. This was at
-		// 128 before, and was still underrunning. It's
-		// important to notice this in addition to the SDL
-		// audio buffer.
-		m.chans[i] = make(chan int32, 384)
+		m.chans[i] = make(chan int32, bufSize)
 		go m.startPair(i)
 	}
 
@@ -152,6 +195,16 @@ func (m *Mixer) Start(output chan int16, srate uint32) {
 // This is ran multiple times per beat in order to update various data.
 // It coincides with sequence callbacks.
 func (m *Mixer) tick() {
+	for i, ins := range m.pending {
+		if ins == nil {
+			continue
+		}
+		m.Ch[i*2].PairMode, m.Ch[i*2+1].PairMode = ins.PairMode, ins.PairMode
+		applyChannel(&m.Ch[i*2], &ins.Left)
+		applyChannel(&m.Ch[i*2+1], &ins.Right)
+		m.pending[i] = nil
+	}
+
 	for i := range m.Ch {
 		c := &m.Ch[i]
 
@@ -205,9 +258,25 @@ func (m *Mixer) tick() {
 			c.FilterLen = 1
 		}
 
+		// Recompute the SVF coefficients; cutoff can be swept by the
+		// sequencer and is also pushed by the amplitude envelope via
+		// FilterEnv, a common tracker/synth modulation.
+		if c.FilterMode != FiltOff {
+			cutoffHz := Note(c.FilterCut + (c.Vol * c.FilterEnv >> 16))
+			if nyquist := float64(m.srate) / 2; cutoffHz > nyquist {
+				cutoffHz = nyquist
+			}
+			c.filterF = 2 * math.Sin(math.Pi*cutoffHz/float64(m.srate))
+			q := 0.5 + float64(c.FilterRes)/float64(0x10000)*19.5
+			c.filterQ = 1 / q
+		}
+
 		// Set pitch
 		c.period = uint32(float64(c.Len/m.srate) * Note(c.Note+vibratoOut))
 	}
+	for i := range m.Voices {
+		tickVoice(m, &m.Voices[i])
+	}
 	m.nextTick = 60*m.srate/m.BPM/m.TickRate + m.count
 	m.tickCount++
 }
@@ -229,6 +298,12 @@ func (m *Mixer) startPair(i int) {
 
 		// Get a wave output
 		dry := int32(m.wave(c.Wave, phase)) * (c.Vol + c.tremoloOut) >> 16
+		if c.FilterMode != FiltOff {
+			dry = c.svf(dry)
+		}
+		if c.Shape != ShapeOff {
+			dry = c.shape(dry)
+		}
 
 		// Store history for delay effect
 		c.hist[c.histHead] = dry + c.delayAvg*c.Feedback>>16
@@ -259,6 +334,13 @@ func (m *Mixer) startPair(i int) {
 			rwave := wave(r, lwave)
 			m.chans[i] <- rwave
 			m.chans[i] <- rwave
+		case PairFM:
+			// Evaluate the 4-op FM voice riding this pair instead
+			// of l/r directly; both channels get the same mix,
+			// as with the other combined modes above.
+			fmOut := evalVoice(m, &m.Voices[i])
+			m.chans[i] <- fmOut
+			m.chans[i] <- fmOut
 		case PairAM:
 			// Modulate amplitude of both waves
 			phase(l)
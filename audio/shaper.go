@@ -0,0 +1,97 @@
+package audio
+
+// Post-envelope, pre-mix nonlinear waveshaping, modeled on the wshaper/
+// genSaturator/mildSaturator/hardSaturator family in the Csound Air
+// module. Runs from startPair's wave closure before the delay-line
+// write, so a shaped signal can feed back through the existing echo,
+// and composes cleanly with the SVF and the PairPM chain since all of
+// them just transform the same per-sample int32. PairFM voices are
+// evaluated outside that closure, so their output is not shaped.
+
+const ( // Channel.Shape
+	ShapeOff = iota
+	ShapeSoftTanh
+	ShapeHardClip
+	ShapeFoldback
+	ShapeBitcrush
+	ShapeLookup
+)
+
+const foldbackPeak int32 = 0x6000
+
+// shape runs one sample of in through c's waveshaper (if enabled) and
+// crossfades the result back with the dry signal by c.ShapeMix.
+func (c *Channel) shape(in int32) int32 {
+	if c.Shape == ShapeOff {
+		return in
+	}
+	driven := in * c.Drive >> 16
+
+	var wet int32
+	switch c.Shape {
+	case ShapeSoftTanh:
+		wet = softTanh(driven)
+	case ShapeHardClip:
+		wet = clamp16(driven)
+	case ShapeFoldback:
+		wet = foldback(driven, foldbackPeak)
+	case ShapeBitcrush:
+		wet = bitcrush(driven, c.Drive)
+	case ShapeLookup:
+		wet = lookupShape(c.ShapeTable, driven)
+	default:
+		wet = driven
+	}
+	return in + (wet-in)*c.ShapeMix>>16
+}
+
+// softTanh is a cheap, integer-friendly cubic approximation of tanh,
+// treating x as a normalized value across the full int16 range.
+func softTanh(x int32) int32 {
+	x = clamp16(x)
+	x2 := x * x >> 15
+	x3 := x2 * x >> 15
+	return clamp16(x - x3/3)
+}
+
+func foldback(x, peak int32) int32 {
+	if peak <= 0 {
+		return 0
+	}
+	for x > peak || x < -peak {
+		if x > peak {
+			x = 2*peak - x
+		} else {
+			x = -2*peak - x
+		}
+	}
+	return x
+}
+
+// bitcrush masks off low bits of x; how many is driven by drive (Q16),
+// so dialing up Drive also dials up the crush.
+func bitcrush(x, drive int32) int32 {
+	bits := uint(drive >> 12) // Drive 0..0x10000 -> 0..15 bits masked
+	if bits > 15 {
+		bits = 15
+	}
+	return x &^ (1<<bits - 1)
+}
+
+// lookupShape treats table as a waveshaping curve spanning the full
+// int16 range and linearly interpolates x's position in it.
+func lookupShape(table []int16, x int32) int32 {
+	if len(table) < 2 {
+		return x
+	}
+	x = clamp16(x)
+	pos := (int64(x) + 0x8000) * int64(len(table)-1) / 0x10000
+	if pos < 0 {
+		pos = 0
+	} else if pos >= int64(len(table)-1) {
+		pos = int64(len(table)) - 2
+	}
+	frac := (int64(x) + 0x8000) * int64(len(table)-1) % 0x10000
+	a, b := int64(table[pos]), int64(table[pos+1])
+	return int32(a + (b-a)*frac/0x10000)
+}
@@ -0,0 +1,81 @@
+package audio
+
+// VoiceManager sits above a Mixer's channel pairs and assigns incoming
+// logical notes (by an opaque voiceKey, e.g. a MIDI channel+note pair)
+// to a free pair, classic voice-stealing when the song or performance
+// asks for more polyphony than the mixer has pairs for.
+type VoiceManager struct {
+	m     *Mixer
+	pairs []pairState
+}
+
+type pairState struct {
+	key       uint32
+	startTick uint32
+	active    bool
+	releasing bool
+}
+
+// NewVoiceManager manages every pair on m.
+func NewVoiceManager(m *Mixer) *VoiceManager {
+	return &VoiceManager{m: m, pairs: make([]pairState, len(m.Voices))}
+}
+
+// NoteOn assigns voiceKey to a pair (preferring a free one, else the
+// oldest releasing one, else the oldest playing one overall), applies
+// ins to it, and triggers the note.
+func (vm *VoiceManager) NoteOn(voiceKey uint32, ins *PairInstrument, note int32, vel int32) {
+	pair := vm.allocate()
+	vm.pairs[pair] = pairState{key: voiceKey, startTick: vm.m.count, active: true}
+	if ins != nil {
+		vm.m.ApplyInstrument(pair, ins)
+	}
+	vol := vel << 9 // vel 0..127 -> roughly 0..0x10000 (Q16)
+	vm.m.OnPair(pair, func(c *Channel) {
+		c.Note = note
+		c.Vol = vol
+		c.EnvPos = 0
+		c.NoteOn = true
+	})
+}
+
+// NoteOff releases every pair currently playing voiceKey (normally
+// just one), moving it into its envelope release rather than cutting
+// it dead, matching how Channel.NoteOn already works.
+func (vm *VoiceManager) NoteOff(voiceKey uint32) {
+	for i := range vm.pairs {
+		if vm.pairs[i].active && !vm.pairs[i].releasing && vm.pairs[i].key == voiceKey {
+			vm.pairs[i].releasing = true
+			vm.m.OnPair(i, func(c *Channel) { c.NoteOn = false })
+		}
+	}
+}
+
+// allocate picks which pair NoteOn should use next.
+func (vm *VoiceManager) allocate() int {
+	for i := range vm.pairs {
+		if !vm.pairs[i].active {
+			return i
+		}
+	}
+	if pair, ok := vm.oldest(true); ok {
+		return pair
+	}
+	pair, _ := vm.oldest(false)
+	return pair
+}
+
+// oldest returns the index of the oldest-started pair, optionally
+// restricted to pairs that are in release.
+func (vm *VoiceManager) oldest(releasingOnly bool) (int, bool) {
+	best, bestTick, found := 0, ^uint32(0), false
+	for i := range vm.pairs {
+		if releasingOnly && !vm.pairs[i].releasing {
+			continue
+		}
+		if vm.pairs[i].startTick < bestTick {
+			best, bestTick, found = i, vm.pairs[i].startTick, true
+		}
+	}
+	return best, found
+}
@@ -0,0 +1,148 @@
+/* A small MIDI 1.0 byte-stream reader, just enough to drive an
+ * audio.VoiceManager from a hardware/USB keyboard or a .mid file's
+ * raw event stream. This deliberately doesn't parse a .mid container
+ * (track chunks, delta-time varints) - it decodes the channel-voice
+ * message bytes, which is the part that actually turns into notes.
+ */
+
+package midi
+
+import (
+	"fmt"
+	"io"
+
+	"hertz4/fakechip/audio"
+)
+
+// Event is one decoded MIDI channel-voice message (or a bare status
+// byte for realtime/system messages, which carry no data bytes).
+type Event struct {
+	Status byte
+	Data1  byte
+	Data2  byte
+}
+
+func (e Event) Type() byte    { return e.Status & 0xf0 }
+func (e Event) Channel() byte { return e.Status & 0x0f }
+
+const (
+	NoteOff  = 0x80
+	NoteOn   = 0x90
+	CtrlChng = 0xb0
+)
+
+// Reader decodes MIDI events from a byte stream, honoring running
+// status (repeated channel-voice messages omit the status byte, as
+// most USB MIDI controllers send them).
+type Reader struct {
+	r       io.Reader
+	running byte
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: r}
+}
+
+// Next decodes and returns the next event, or an error (io.EOF at a
+// clean end of stream).
+func (rd *Reader) Next() (Event, error) {
+	b, err := rd.readByte()
+	if err != nil {
+		return Event{}, err
+	}
+
+	var status, first byte
+	haveFirst := false
+	if b&0x80 != 0 {
+		status = b
+	} else {
+		if rd.running == 0 {
+			return Event{}, fmt.Errorf("midi: data byte %#x with no running status", b)
+		}
+		status, first, haveFirst = rd.running, b, true
+	}
+
+	if status >= 0xf8 { // Realtime: no data, doesn't touch running status
+		return Event{Status: status}, nil
+	}
+	if status >= 0xf0 { // System common/exclusive: best-effort, clears running status
+		rd.running = 0
+		return Event{Status: status}, nil
+	}
+
+	rd.running = status
+	ev := Event{Status: status}
+	n := dataBytes(status)
+	if n >= 1 {
+		if haveFirst {
+			ev.Data1 = first
+		} else if ev.Data1, err = rd.readByte(); err != nil {
+			return Event{}, err
+		}
+	}
+	if n >= 2 {
+		if ev.Data2, err = rd.readByte(); err != nil {
+			return Event{}, err
+		}
+	}
+	return ev, nil
+}
+
+func dataBytes(status byte) int {
+	switch status & 0xf0 {
+	case 0xc0, 0xd0:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func (rd *Reader) readByte() (byte, error) {
+	var buf [1]byte
+	_, err := io.ReadFull(rd.r, buf[:])
+	return buf[0], err
+}
+
+// voiceKey turns a channel+note pair into the opaque key
+// audio.VoiceManager tracks NoteOn/NoteOff by.
+func voiceKey(ev Event) uint32 {
+	return uint32(ev.Channel())<<8 | uint32(ev.Data1)
+}
+
+// Drive reads events from r until EOF or an error, feeding Note On/Off
+// messages into vm using ins as the patch for every new note.
+func Drive(r io.Reader, vm *audio.VoiceManager, ins *audio.PairInstrument) error {
+	rd := NewReader(r)
+	for {
+		ev, err := rd.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		apply(ev, vm, ins)
+	}
+}
+
+// DriveChan is the same as Drive, but for callers that already have
+// their own decoded event stream (e.g. from a platform MIDI API)
+// instead of a raw byte io.Reader.
+func DriveChan(events <-chan Event, vm *audio.VoiceManager, ins *audio.PairInstrument) {
+	for ev := range events {
+		apply(ev, vm, ins)
+	}
+}
+
+func apply(ev Event, vm *audio.VoiceManager, ins *audio.PairInstrument) {
+	switch ev.Type() {
+	case NoteOn:
+		if ev.Data2 == 0 { // Velocity 0 note-on is a note-off, by convention
+			vm.NoteOff(voiceKey(ev))
+		} else {
+			vm.NoteOn(voiceKey(ev), ins, int32(ev.Data1)<<16, int32(ev.Data2))
+		}
+	case NoteOff:
+		vm.NoteOff(voiceKey(ev))
+	}
+}
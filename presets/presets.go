@@ -0,0 +1,150 @@
+/* A built-in instrument library for fakechip, in the spirit of the
+ * fixed instrument ROM baked into VRC7-style FM chips: a small bank of
+ * ready-to-use patches so users get a real sound library instead of
+ * hand-tuning every audio.Instrument field per song.
+ */
+
+package presets
+
+import "hertz4/fakechip/audio"
+
+// Preset pairs a lookup name with its patch.
+type Preset struct {
+	Name  string
+	Patch audio.PairInstrument
+}
+
+// stereo builds a PairInstrument that plays the same Instrument on both
+// sides of the pair, for patches (PairStereo, PairAM, PairSync) whose
+// left and right need no separate modulator/carrier settings.
+func stereo(mode int, ins audio.Instrument) audio.PairInstrument {
+	return audio.PairInstrument{PairMode: mode, Left: ins, Right: ins}
+}
+
+// Bank is the built-in patch bank, indexable by name (ByName) or
+// position (ByNumber).
+var Bank = []Preset{
+	{"bass", stereo(audio.PairStereo, audio.Instrument{
+		Attack: 0x2000, Peak: 0x10000, Decay: 0x800, Sustain: 0x6000, Release: 0x1000,
+		DryLevel:   0x10000,
+		FilterMode: audio.FiltLP, FilterCut: 48 << 16, FilterRes: 0x4000, FilterEnv: 0x2000,
+	})},
+	{"pluck", stereo(audio.PairStereo, audio.Instrument{
+		Attack: 0x10000, Peak: 0x10000, Decay: 0x2000, Sustain: 0, Release: 0x1800,
+		DryLevel:   0x10000,
+		FilterMode: audio.FiltLP, FilterCut: 72 << 16, FilterRes: 0x2000, FilterEnv: 0x6000,
+	})},
+	{"lead", stereo(audio.PairStereo, audio.Instrument{
+		Attack: 0x6000, Peak: 0x10000, Decay: 0x400, Sustain: 0xc000, Release: 0x1000,
+		Vibrato: 0x400, VibratoRate: 0x2000,
+		DryLevel: 0x10000,
+		Shape:    audio.ShapeSoftTanh, Drive: 0x18000, ShapeMix: 0x8000,
+	})},
+	{"pad", stereo(audio.PairStereo, audio.Instrument{
+		Attack: 0x400, Peak: 0xc000, Decay: 0x200, Sustain: 0xc000, Release: 0x400,
+		Tremolo: 0x800, TremoloRate: 0x800,
+		DryLevel: 0xc000, WetLevel: 0x6000, Feedback: 0x4000, FilterLen: 8, DelayTicks: 2,
+		FilterMode: audio.FiltLP, FilterCut: 60 << 16, FilterRes: 0x1000,
+	})},
+	{"kick", stereo(audio.PairStereo, audio.Instrument{
+		Attack: 0x10000, Peak: 0x10000, Decay: 0x4000, Sustain: 0, Release: 0x3000,
+		DryLevel:   0x10000,
+		FilterMode: audio.FiltLP, FilterCut: 36 << 16, FilterRes: 0x6000,
+		Shape: audio.ShapeHardClip, Drive: 0x14000, ShapeMix: 0x6000,
+	})},
+	{"snare", stereo(audio.PairStereo, audio.Instrument{
+		Attack: 0x10000, Peak: 0xc000, Decay: 0x6000, Sustain: 0, Release: 0x4000,
+		DryLevel:   0x10000,
+		FilterMode: audio.FiltBP, FilterCut: 64 << 16, FilterRes: 0x8000,
+	})},
+	{"hat", stereo(audio.PairStereo, audio.Instrument{
+		Attack: 0x10000, Peak: 0x8000, Decay: 0x8000, Sustain: 0, Release: 0x6000,
+		DryLevel:   0x10000,
+		FilterMode: audio.FiltHP, FilterCut: 96 << 16, FilterRes: 0x2000,
+	})},
+	{"bell", audio.PairInstrument{
+		PairMode: audio.PairPM,
+		// Left is the modulator: quick decay, no sustain.
+		Left: audio.Instrument{
+			Attack: 0x10000, Peak: 0x10000, Decay: 0x2000, Sustain: 0, Release: 0x1000,
+			DryLevel: 0x10000,
+		},
+		// Right is the carrier, rings out longer.
+		Right: audio.Instrument{
+			Attack: 0x10000, Peak: 0x10000, Decay: 0x800, Sustain: 0x2000, Release: 0x1800,
+			DryLevel: 0x10000,
+		},
+	}},
+	{"brass", audio.PairInstrument{
+		PairMode: audio.PairPM,
+		Left: audio.Instrument{
+			Attack: 0x8000, Peak: 0xa000, Decay: 0, Sustain: 0xa000, Release: 0x1000,
+			DryLevel: 0x10000,
+		},
+		Right: audio.Instrument{
+			Attack: 0x4000, Peak: 0x10000, Decay: 0x800, Sustain: 0xa000, Release: 0x1000,
+			Vibrato: 0x300, VibratoRate: 0x1800,
+			DryLevel: 0x10000,
+			Shape:    audio.ShapeSoftTanh, Drive: 0x1c000, ShapeMix: 0x6000,
+		},
+	}},
+	{"strings", stereo(audio.PairStereo, audio.Instrument{
+		Attack: 0x800, Peak: 0xc000, Decay: 0x200, Sustain: 0xc000, Release: 0x400,
+		Tremolo: 0x400, TremoloRate: 0x400,
+		DryLevel: 0xc000, WetLevel: 0x4000, Feedback: 0x3000, FilterLen: 16, DelayTicks: 3,
+		FilterMode: audio.FiltLP, FilterCut: 66 << 16, FilterRes: 0x800,
+	})},
+	{"organ", stereo(audio.PairAM, audio.Instrument{
+		Attack: 0x10000, Peak: 0x10000, Decay: 0, Sustain: 0x10000, Release: 0x2000,
+		DryLevel: 0x10000,
+	})},
+	{"synclead", stereo(audio.PairSync, audio.Instrument{
+		Attack: 0x8000, Peak: 0x10000, Decay: 0x1000, Sustain: 0x8000, Release: 0x1000,
+		DryLevel:   0x10000,
+		FilterMode: audio.FiltLP, FilterCut: 80 << 16, FilterRes: 0x8000, FilterEnv: 0x4000,
+	})},
+	{"epiano", audio.PairInstrument{
+		PairMode: audio.PairPM,
+		Left: audio.Instrument{
+			Attack: 0x10000, Peak: 0xa000, Decay: 0x1000, Sustain: 0, Release: 0x1800,
+			DryLevel: 0x10000,
+		},
+		Right: audio.Instrument{
+			Attack: 0x10000, Peak: 0x10000, Decay: 0x1800, Sustain: 0x3000, Release: 0x1800,
+			DryLevel: 0x10000,
+		},
+	}},
+	{"clav", stereo(audio.PairAM, audio.Instrument{
+		Attack: 0x10000, Peak: 0xa000, Decay: 0x5000, Sustain: 0, Release: 0x3000,
+		DryLevel:   0x10000,
+		FilterMode: audio.FiltBP, FilterCut: 70 << 16, FilterRes: 0x6000,
+	})},
+	{"marimba", stereo(audio.PairStereo, audio.Instrument{
+		Attack: 0x10000, Peak: 0xd000, Decay: 0x3000, Sustain: 0, Release: 0x2000,
+		DryLevel:   0x10000,
+		FilterMode: audio.FiltLP, FilterCut: 54 << 16, FilterRes: 0x1000,
+	})},
+	{"choir", stereo(audio.PairStereo, audio.Instrument{
+		Attack: 0x200, Peak: 0x9000, Decay: 0x100, Sustain: 0x9000, Release: 0x300,
+		Vibrato: 0x300, VibratoRate: 0x300,
+		DryLevel: 0xa000, WetLevel: 0x5000, Feedback: 0x5000, FilterLen: 24, DelayTicks: 4,
+	})},
+}
+
+// ByName returns the bank patch with the given name.
+func ByName(name string) (audio.PairInstrument, bool) {
+	for _, p := range Bank {
+		if p.Name == name {
+			return p.Patch, true
+		}
+	}
+	return audio.PairInstrument{}, false
+}
+
+// ByNumber returns the nth bank patch.
+func ByNumber(n int) (audio.PairInstrument, bool) {
+	if n < 0 || n >= len(Bank) {
+		return audio.PairInstrument{}, false
+	}
+	return Bank[n].Patch, true
+}
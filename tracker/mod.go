@@ -0,0 +1,145 @@
+package tracker
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// amigaPeriods is the standard ProTracker period table, one octave per
+// row (C-1 through B-3), used to translate raw period values in a MOD
+// file into note numbers.
+var amigaPeriods = [3][12]uint16{
+	{856, 808, 762, 720, 678, 640, 604, 570, 538, 508, 480, 453},
+	{428, 404, 381, 360, 339, 320, 302, 285, 269, 254, 240, 226},
+	{214, 202, 190, 180, 170, 160, 151, 143, 135, 127, 120, 113},
+}
+
+// periodToNote maps an Amiga period value to a midi-style note number
+// (36 = C-1 in ProTracker's own numbering), or 0 if period is silent.
+func periodToNote(period uint16) int32 {
+	if period == 0 {
+		return 0
+	}
+	best, bestNote := uint16(0xffff), int32(36)
+	for octave, row := range amigaPeriods {
+		for semi, p := range row {
+			d := p - period
+			if period > p {
+				d = period - p
+			}
+			if d < best {
+				best = d
+				bestNote = int32(36 + octave*12 + semi)
+			}
+		}
+	}
+	return bestNote
+}
+
+var magicChannels = map[string]int{
+	"M.K.": 4, "M!K!": 4, "FLT4": 4, "4CHN": 4,
+	"6CHN": 6, "8CHN": 8, "FLT8": 8,
+}
+
+// LoadMOD parses a 4- or 8-channel ProTracker .mod file (the classic
+// "M.K."/"8CHN"-tagged 31-sample format) into a Song.
+func LoadMOD(r io.Reader) (*Song, error) {
+	br := bufio.NewReader(r)
+	buf := make([]byte, 1084)
+	if _, err := io.ReadFull(br, buf); err != nil {
+		return nil, fmt.Errorf("tracker: reading MOD header: %w", err)
+	}
+
+	sig := string(buf[1080:1084])
+	numChans, ok := magicChannels[sig]
+	if !ok {
+		return nil, fmt.Errorf("tracker: unrecognized MOD signature %q", sig)
+	}
+
+	numSamples := 31
+	instruments := make([]Instrument, numSamples)
+	sampleLen := make([]int, numSamples)
+	off := 20
+	for i := 0; i < numSamples; i++ {
+		h := buf[off : off+30]
+		name := trimName(h[0:22])
+		length := int(h[22])<<8 | int(h[23])
+		finetune := int8(h[24]<<4) >> 4 // low nibble, sign-extended
+		volume := int32(h[25])
+		instruments[i] = Instrument{
+			Name:     name,
+			Wave:     0, // fakechip has no sample playback; caller assigns real waves
+			Volume:   volume << 10,
+			Finetune: int32(finetune) << 10,
+		}
+		sampleLen[i] = length * 2
+		off += 30
+	}
+
+	songLen := int(buf[950])
+	orderTable := buf[952:1080]
+	orders := make([]int, 0, songLen)
+	maxPattern := 0
+	for i := 0; i < songLen; i++ {
+		o := int(orderTable[i])
+		orders = append(orders, o)
+		if o > maxPattern {
+			maxPattern = o
+		}
+	}
+
+	patterns := make([]Pattern, maxPattern+1)
+	rowBytes := numChans * 4
+	patBuf := make([]byte, rowBytes)
+	for p := 0; p <= maxPattern; p++ {
+		pat := make(Pattern, 64)
+		for row := 0; row < 64; row++ {
+			if _, err := io.ReadFull(br, patBuf); err != nil {
+				return nil, fmt.Errorf("tracker: reading pattern %d row %d: %w", p, row, err)
+			}
+			cells := make([]Cell, numChans)
+			for c := 0; c < numChans; c++ {
+				b := patBuf[c*4 : c*4+4]
+				period := uint16(b[0]&0x0f)<<8 | uint16(b[1])
+				sample := (b[0] & 0xf0) | (b[2] >> 4)
+				cells[c] = Cell{
+					Note:        periodToNote(period),
+					Instrument:  int32(sample),
+					Volume:      -1,
+					Effect:      b[2] & 0x0f,
+					EffectParam: b[3],
+				}
+			}
+			pat[row] = cells
+		}
+		patterns[p] = pat
+	}
+
+	// Sample PCM data follows the patterns; fakechip doesn't play back
+	// samples, so it's skipped rather than retained.
+	for i := 0; i < numSamples; i++ {
+		if sampleLen[i] == 0 {
+			continue
+		}
+		if _, err := io.CopyN(io.Discard, br, int64(sampleLen[i])); err != nil {
+			return nil, fmt.Errorf("tracker: skipping sample %d data: %w", i, err)
+		}
+	}
+
+	return &Song{
+		Orders:      orders,
+		Patterns:    patterns,
+		Instruments: instruments,
+		InitBPM:     125,
+		InitSpeed:   6,
+	}, nil
+}
+
+func trimName(b []byte) string {
+	end := len(b)
+	for end > 0 && b[end-1] == 0 {
+		end--
+	}
+	return string(b[:end])
+}
@@ -0,0 +1,99 @@
+package tracker
+
+import (
+	"bytes"
+	"testing"
+)
+
+// buildMinimalMOD assembles a 4-channel "M.K." MOD file with 31 empty
+// (zero-length) samples, a single order pointing at pattern 0, and one
+// 64-row pattern built from the given row data (each row already
+// encoded as its 4 raw per-channel bytes).
+func buildMinimalMOD(rows [64][4][4]byte) []byte {
+	buf := make([]byte, 0, 1084+64*16)
+	buf = append(buf, make([]byte, 20)...)    // title
+	buf = append(buf, make([]byte, 31*30)...) // 31 empty sample headers
+	buf = append(buf, 1, 0)                   // song length 1, restart 0
+	orders := make([]byte, 128)               // order 0 -> pattern 0, rest unused
+	buf = append(buf, orders...)
+	buf = append(buf, []byte("M.K.")...)
+	for _, row := range rows {
+		for _, cell := range row {
+			buf = append(buf, cell[:]...)
+		}
+	}
+	return buf
+}
+
+func TestLoadMODParsesNoteInstrumentAndEffect(t *testing.T) {
+	var rows [64][4][4]byte
+	// Channel 0, row 0: period 428 (-> note 48), sample 1, effect Axy
+	// volume slide with param 0x0f.
+	rows[0][0] = [4]byte{0x01, 0xAC, 0x1A, 0x0F}
+
+	data := buildMinimalMOD(rows)
+	song, err := LoadMOD(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("LoadMOD: %v", err)
+	}
+
+	if got, want := len(song.Orders), 1; got != want {
+		t.Fatalf("len(Orders) = %d, want %d", got, want)
+	}
+	if got, want := len(song.Patterns), 1; got != want {
+		t.Fatalf("len(Patterns) = %d, want %d", got, want)
+	}
+	pat := song.Patterns[0]
+	if got, want := len(pat), 64; got != want {
+		t.Fatalf("len(pattern rows) = %d, want %d", got, want)
+	}
+	cell := pat[0][0]
+	if got, want := cell.Note, int32(48); got != want {
+		t.Errorf("Note = %d, want %d", got, want)
+	}
+	if got, want := cell.Instrument, int32(1); got != want {
+		t.Errorf("Instrument = %d, want %d", got, want)
+	}
+	if got, want := cell.Effect, uint8(EffVolSlide); got != want {
+		t.Errorf("Effect = %#x, want %#x", got, want)
+	}
+	if got, want := cell.EffectParam, uint8(0x0f); got != want {
+		t.Errorf("EffectParam = %#x, want %#x", got, want)
+	}
+
+	// An empty cell (all zero bytes) should decode as no note, no
+	// instrument, and Volume left at the "unset" sentinel.
+	empty := pat[0][1]
+	if empty.Note != 0 || empty.Instrument != 0 {
+		t.Errorf("empty cell decoded as Note=%d Instrument=%d, want 0/0", empty.Note, empty.Instrument)
+	}
+	if empty.Volume != -1 {
+		t.Errorf("empty cell Volume = %d, want -1 (unset)", empty.Volume)
+	}
+}
+
+// The finetune nibble is signed (-8..7); a negative value must not come
+// out positive from a bad sign-extension of the unsigned header byte.
+func TestLoadMODSignExtendsNegativeFinetune(t *testing.T) {
+	var rows [64][4][4]byte
+	data := buildMinimalMOD(rows)
+	data[20+24] = 0xf8 // sample 1 header, finetune byte: low nibble 0x8 = -8
+
+	song, err := LoadMOD(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("LoadMOD: %v", err)
+	}
+	if got, want := song.Instruments[0].Finetune, int32(-8)<<10; got != want {
+		t.Errorf("Finetune = %d, want %d", got, want)
+	}
+}
+
+func TestLoadMODRejectsUnknownSignature(t *testing.T) {
+	var rows [64][4][4]byte
+	data := buildMinimalMOD(rows)
+	copy(data[1080:1084], []byte("XXXX"))
+
+	if _, err := LoadMOD(bytes.NewReader(data)); err == nil {
+		t.Fatal("LoadMOD with an unrecognized signature: want error, got nil")
+	}
+}
@@ -0,0 +1,350 @@
+/* A classic tracker-style sequencer that plugs into audio.NewMixer as
+ * the seq callback, turning the mixer into a pattern player in the
+ * spirit of Hemkay's mixSong/performTicks.
+ *
+ * Song data (orders, patterns, instruments) is independent of the
+ * underlying chip's NumChans: a song can have more tracker channels
+ * than the mixer has physical channel pairs, in which case Player
+ * steals the least-recently-triggered physical channel the same way
+ * a real tracker voice-steals when polyphony runs out.
+ *
+ * Note values in a Cell are 0 for "no note", otherwise a midi note
+ * number, shifted into the mixer's midi-note-number-in-high-16-bits
+ * encoding when written to a Channel.
+ */
+
+package tracker
+
+import "hertz4/fakechip/audio"
+
+// One pattern cell: a note/instrument/volume column, plus one effect.
+// Note and Instrument are 0 when not present in the cell; Volume is -1
+// when the volume column wasn't set (0 is a valid "silence" volume).
+type Cell struct {
+	Note, Instrument, Volume int32
+	Effect, EffectParam      uint8
+}
+
+// A Pattern is rows of cells, one slice of cells per row (one Cell per
+// tracker channel).
+type Pattern [][]Cell
+
+// A Song is everything that comes from the module file: the order
+// list, the patterns it indexes into, the instrument bank, and initial
+// tempo.
+type Song struct {
+	Orders      []int
+	Patterns    []Pattern
+	Instruments []Instrument
+	InitBPM     uint32
+	InitSpeed   uint32
+}
+
+// Instrument is deliberately minimal for now: fakechip has no sample
+// playback, so a loaded instrument just selects a built-in waveform
+// and a default volume/finetune. Richer patches come from
+// audio.PairInstrument and the presets package.
+type Instrument struct {
+	Name     string
+	Wave     int
+	Volume   int32 // Q16
+	Finetune int32 // Added to Note, same 16.16 encoding
+}
+
+// Effect commands understood by Player, using the usual tracker
+// hex-digit naming.
+const (
+	EffArpeggio      = 0x0
+	EffPortaUp       = 0x1
+	EffPortaDown     = 0x2
+	EffTonePorta     = 0x3
+	EffVibrato       = 0x4
+	EffTremolo       = 0x7
+	EffVolSlide      = 0xA
+	EffPositionJump  = 0xB
+	EffPatternBreak  = 0xD
+	EffSetSpeedTempo = 0xF
+)
+
+// Per tracker-channel memory: the things effects need carried between
+// rows, plus which physical mixer channel pair this tracker channel is
+// currently riding.
+type channelState struct {
+	pair        int    // Index into mixer pairs (m.Ch[pair*2], m.Ch[pair*2+1])
+	lastTrigger uint32 // Tick count at which this pair was last note-on'd, for voice stealing
+
+	portaTarget  int32 // 16.16 note to slide towards for EffTonePorta
+	portaSpeed   int32
+	arpBase      int32
+	arpNotes     [3]int32
+	volSlideRate int32
+}
+
+// Player walks a Song's orders/patterns/rows and drives a *audio.Mixer
+// by writing into its channels, the same way a human or another
+// program would poke Mixer.Ch directly.
+type Player struct {
+	Song *Song
+
+	ticksPerRow uint32 // TickSpeed worth of calls == one row
+	tick        uint32 // Tick within the current row
+	order       int
+	row         int
+
+	jumpOrder int // -1 = no pending jump
+	jumpRow   int
+
+	chans []channelState
+	tick0 uint32 // Global tick counter, for voice-stealing "oldest" comparisons
+
+	pairs int // Number of physical channel pairs available on the mixer
+}
+
+// NewPlayer sets up a Player for song, sized for a mixer with the given
+// number of physical channels (len(mixer.Ch), i.e. twice its pairs).
+func NewPlayer(song *Song, physChans int) *Player {
+	width := 0
+	if len(song.Patterns) > 0 {
+		width = len(song.Patterns[0][0])
+	}
+	pairs := physChans / 2
+	p := &Player{
+		Song:        song,
+		ticksPerRow: song.InitSpeed,
+		jumpOrder:   -1,
+		chans:       make([]channelState, width),
+		pairs:       pairs,
+	}
+	for i := range p.chans {
+		p.chans[i].pair = i % pairs
+		p.chans[i].lastTrigger = ^uint32(0)
+	}
+	return p
+}
+
+// Tick is passed as the seq callback to audio.NewMixer. It advances one
+// row at the start of each row and applies per-tick effects every call.
+func (p *Player) Tick(m *audio.Mixer) {
+	if p.ticksPerRow == 0 {
+		p.ticksPerRow = 1
+	}
+	if m.TickSpeed != p.ticksPerRow {
+		m.TickSpeed = p.ticksPerRow
+	}
+
+	if p.tick == 0 {
+		p.startRow(m)
+	}
+	p.applyTickEffects(m)
+
+	p.tick++
+	p.tick0++
+	if p.tick >= p.ticksPerRow {
+		p.tick = 0
+		p.advanceRow()
+	}
+}
+
+// pickPair returns the physical pair index for tracker channel ch to
+// trigger a new note on: its existing assignment if that channel isn't
+// needed elsewhere, else (when song channels outnumber physical ones)
+// the least-recently-triggered pair, classic voice stealing.
+func (p *Player) pickPair(ch int) int {
+	if len(p.chans) <= p.pairs {
+		return ch % p.pairs
+	}
+	// A pair's last trigger is the latest among the channels riding it;
+	// -1 means no channel has triggered it yet.
+	last := make([]int64, p.pairs)
+	for i := range last {
+		last[i] = -1
+	}
+	for i := range p.chans {
+		t := p.chans[i].lastTrigger
+		if t != ^uint32(0) && int64(t) > last[p.chans[i].pair] {
+			last[p.chans[i].pair] = int64(t)
+		}
+	}
+	oldest := 0
+	for i := range last {
+		if last[i] < last[oldest] {
+			oldest = i
+		}
+	}
+	return oldest
+}
+
+func (p *Player) startRow(m *audio.Mixer) {
+	if p.order >= len(p.Song.Orders) {
+		p.order = 0
+	}
+	pat := p.Song.Patterns[p.Song.Orders[p.order]]
+	if p.row >= len(pat) {
+		p.row = 0
+	}
+	row := pat[p.row]
+
+	for ci := range row {
+		if ci >= len(p.chans) {
+			break
+		}
+		cell := row[ci]
+		cs := &p.chans[ci]
+
+		if cell.Note != 0 && cell.Effect != EffTonePorta {
+			cs.pair = p.pickPair(ci)
+			cs.lastTrigger = p.tick0
+		}
+
+		l, r := &m.Ch[cs.pair*2], &m.Ch[cs.pair*2+1]
+
+		// Slides, fades, vibrato and tremolo are one-row commands in
+		// ProTracker: clear them at the start of every row so
+		// startEffect only re-arms what this row actually asks for,
+		// instead of a stale 1xx/2xx/4xy/7xy/Axy running forever in
+		// Mixer.tick.
+		l.Slide, r.Slide = 0, 0
+		l.Fade, r.Fade = 0, 0
+		l.VibratoRate, r.VibratoRate = 0, 0
+		l.Vibrato, r.Vibrato = 0, 0
+		l.TremoloRate, r.TremoloRate = 0, 0
+		l.Tremolo, r.Tremolo = 0, 0
+
+		if cell.Note != 0 && cell.Effect != EffTonePorta {
+			note := int32(cell.Note) << 16
+			if cell.Instrument > 0 && int(cell.Instrument) <= len(p.Song.Instruments) {
+				ins := p.Song.Instruments[cell.Instrument-1]
+				note += ins.Finetune
+				l.Wave, r.Wave = ins.Wave, ins.Wave
+				l.Vol, r.Vol = ins.Volume, ins.Volume
+			}
+			l.Note, r.Note = note, note
+			l.NoteOn, r.NoteOn = true, true
+			l.EnvPos, r.EnvPos = 0, 0
+		} else if cell.Note != 0 && cell.Effect == EffTonePorta {
+			cs.portaTarget = int32(cell.Note) << 16
+		}
+
+		if cell.Volume >= 0 {
+			l.Vol, r.Vol = cell.Volume<<10, cell.Volume<<10
+		}
+
+		p.startEffect(m, cs, cell)
+	}
+}
+
+// startEffect handles the part of an effect that only needs doing once,
+// on the row it's set (memory setup, one-shot jumps); the rest happens
+// every tick in applyTickEffects.
+func (p *Player) startEffect(m *audio.Mixer, cs *channelState, cell Cell) {
+	switch cell.Effect {
+	case EffArpeggio:
+		// Effect 0 is also the zero value of an effect-less cell, so
+		// only treat this as an arpeggio when EffectParam says so.
+		if cell.EffectParam != 0 {
+			cs.arpBase = int32(cell.Note) << 16
+			cs.arpNotes[0] = 0
+			cs.arpNotes[1] = int32(cell.EffectParam>>4) << 16
+			cs.arpNotes[2] = int32(cell.EffectParam&0xf) << 16
+		}
+	case EffPortaUp:
+		m.Ch[cs.pair*2].Slide = int32(cell.EffectParam) << 10
+		m.Ch[cs.pair*2+1].Slide = int32(cell.EffectParam) << 10
+	case EffPortaDown:
+		m.Ch[cs.pair*2].Slide = -int32(cell.EffectParam) << 10
+		m.Ch[cs.pair*2+1].Slide = -int32(cell.EffectParam) << 10
+	case EffTonePorta:
+		if cell.EffectParam != 0 {
+			cs.portaSpeed = int32(cell.EffectParam) << 10
+		}
+	case EffVibrato:
+		rate := uint32(cell.EffectParam>>4) * p.ticksPerRow
+		depth := int32(cell.EffectParam&0xf) << 10
+		m.Ch[cs.pair*2].VibratoRate, m.Ch[cs.pair*2+1].VibratoRate = rate, rate
+		m.Ch[cs.pair*2].Vibrato, m.Ch[cs.pair*2+1].Vibrato = depth, depth
+	case EffTremolo:
+		rate := uint32(cell.EffectParam>>4) * p.ticksPerRow
+		depth := int32(cell.EffectParam&0xf) << 10
+		m.Ch[cs.pair*2].TremoloRate, m.Ch[cs.pair*2+1].TremoloRate = rate, rate
+		m.Ch[cs.pair*2].Tremolo, m.Ch[cs.pair*2+1].Tremolo = depth, depth
+	case EffVolSlide:
+		up, down := int32(cell.EffectParam>>4), int32(cell.EffectParam&0xf)
+		cs.volSlideRate = (up - down) << 10
+	case EffPositionJump:
+		p.jumpOrder, p.jumpRow = int(cell.EffectParam), 0
+	case EffPatternBreak:
+		if p.jumpOrder < 0 {
+			p.jumpOrder = p.order + 1
+		}
+		p.jumpRow = int(cell.EffectParam>>4)*10 + int(cell.EffectParam&0xf)
+	case EffSetSpeedTempo:
+		if cell.EffectParam < 32 {
+			p.ticksPerRow = uint32(cell.EffectParam)
+		} else {
+			m.BPM = uint32(cell.EffectParam)
+		}
+	}
+}
+
+// applyTickEffects re-applies the effects that change every tick within
+// a row (arpeggio, tone-portamento, volume slide).
+func (p *Player) applyTickEffects(m *audio.Mixer) {
+	pat := p.Song.Patterns[p.Song.Orders[p.order]]
+	row := pat[p.row]
+	for ci := range row {
+		if ci >= len(p.chans) {
+			break
+		}
+		cell := row[ci]
+		cs := &p.chans[ci]
+		l, r := &m.Ch[cs.pair*2], &m.Ch[cs.pair*2+1]
+
+		switch cell.Effect {
+		case EffArpeggio:
+			if cell.EffectParam != 0 {
+				off := cs.arpNotes[p.tick%3]
+				l.Note, r.Note = cs.arpBase+off, cs.arpBase+off
+			}
+		case EffTonePorta:
+			l.Note = tonePorta(l.Note, cs.portaTarget, cs.portaSpeed)
+			r.Note = l.Note
+		case EffVolSlide:
+			l.Fade, r.Fade = cs.volSlideRate, cs.volSlideRate
+		}
+	}
+}
+
+func tonePorta(note, target, speed int32) int32 {
+	if note < target {
+		return min32(note+speed, target)
+	}
+	return max32(note-speed, target)
+}
+
+func min32(a, b int32) int32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max32(a, b int32) int32 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func (p *Player) advanceRow() {
+	if p.jumpOrder >= 0 {
+		p.order, p.row = p.jumpOrder, p.jumpRow
+		p.jumpOrder = -1
+		return
+	}
+	p.row++
+	pat := p.Song.Patterns[p.Song.Orders[p.order%len(p.Song.Orders)]]
+	if p.row >= len(pat) {
+		p.row = 0
+		p.order++
+	}
+}
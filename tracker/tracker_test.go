@@ -0,0 +1,161 @@
+package tracker
+
+import (
+	"testing"
+
+	"hertz4/fakechip/audio"
+)
+
+func silentWave(int, uint32) int16 { return 0 }
+
+func songOf(rows ...Cell) *Song {
+	pat := make(Pattern, len(rows))
+	for i, c := range rows {
+		pat[i] = []Cell{c}
+	}
+	return &Song{
+		Orders:    []int{0},
+		Patterns:  []Pattern{pat},
+		InitBPM:   125,
+		InitSpeed: 1, // One tick per row, to keep the test simple
+	}
+}
+
+// A sustained note must survive an empty row after it. This is a
+// regression test for a bug where EffArpeggio (0x0) being the zero
+// value of Cell.Effect made every effect-less cell rewrite Note to 0.
+func TestArpeggioDoesNotClobberSustainedNote(t *testing.T) {
+	song := songOf(
+		Cell{Note: 48, Volume: -1},
+		Cell{Note: 0, Volume: -1}, // Effect-less row following it
+	)
+	m := audio.NewMixerN(silentWave, nil, 1)
+	p := NewPlayer(song, len(m.Ch))
+
+	p.Tick(&m) // Row 0: strikes the note
+	if got, want := m.Ch[0].Note, int32(48)<<16; got != want {
+		t.Fatalf("after row 0: Note = %#x, want %#x", got, want)
+	}
+
+	p.Tick(&m) // Row 1: no note, no effect
+	if got, want := m.Ch[0].Note, int32(48)<<16; got != want {
+		t.Fatalf("after row 1 (empty): Note = %#x, want %#x (must stay put)", got, want)
+	}
+}
+
+// An actual arpeggio (nonzero EffectParam) should still cycle the note.
+func TestArpeggioCycles(t *testing.T) {
+	song := songOf(Cell{Note: 48, Volume: -1, Effect: EffArpeggio, EffectParam: 0x47})
+	song.InitSpeed = 3
+	m := audio.NewMixerN(silentWave, nil, 1)
+	p := NewPlayer(song, len(m.Ch))
+
+	p.Tick(&m) // tick 0 of the row: startRow + tick 0 of applyTickEffects
+	if got, want := m.Ch[0].Note, int32(48)<<16; got != want {
+		t.Fatalf("tick 0: Note = %#x, want %#x", got, want)
+	}
+	p.Tick(&m) // tick 1: +4 semitones
+	if got, want := m.Ch[0].Note, int32(48+4)<<16; got != want {
+		t.Fatalf("tick 1: Note = %#x, want %#x", got, want)
+	}
+	p.Tick(&m) // tick 2: +7 semitones
+	if got, want := m.Ch[0].Note, int32(48+7)<<16; got != want {
+		t.Fatalf("tick 2: Note = %#x, want %#x", got, want)
+	}
+}
+
+// A portamento (1xx) must only slide for the row it's set on; the next
+// row, with no effect, should stop the slide rather than let
+// Mixer.tick's "Note += Slide" run forever.
+func TestPortaUpIsOneRowOnly(t *testing.T) {
+	song := songOf(
+		Cell{Note: 48, Volume: -1, Effect: EffPortaUp, EffectParam: 0x10},
+		Cell{Volume: -1},
+	)
+	m := audio.NewMixerN(silentWave, nil, 1)
+	p := NewPlayer(song, len(m.Ch))
+
+	p.Tick(&m) // Row 0
+	if m.Ch[0].Slide == 0 {
+		t.Fatalf("row 0: Slide = 0, want nonzero while EffPortaUp is active")
+	}
+
+	p.Tick(&m) // Row 1, no effect
+	if m.Ch[0].Slide != 0 {
+		t.Fatalf("row 1: Slide = %#x, want 0 once the effect row has passed", m.Ch[0].Slide)
+	}
+}
+
+// Same as above, but for Axy volume slide and Fade.
+func TestVolSlideIsOneRowOnly(t *testing.T) {
+	song := songOf(
+		Cell{Note: 48, Volume: -1, Effect: EffVolSlide, EffectParam: 0x0f},
+		Cell{Volume: -1},
+	)
+	m := audio.NewMixerN(silentWave, nil, 1)
+	p := NewPlayer(song, len(m.Ch))
+
+	p.Tick(&m)
+	if m.Ch[0].Fade == 0 {
+		t.Fatalf("row 0: Fade = 0, want nonzero while EffVolSlide is active")
+	}
+
+	p.Tick(&m)
+	if m.Ch[0].Fade != 0 {
+		t.Fatalf("row 1: Fade = %#x, want 0 once the effect row has passed", m.Ch[0].Fade)
+	}
+}
+
+// A 4xy vibrato (or 7xy tremolo) must also only run for the row it's set
+// on, same as Slide/Fade: a later effect-less row should silence the
+// LFO rather than leave it running forever.
+func TestVibratoIsOneRowOnly(t *testing.T) {
+	song := songOf(
+		Cell{Note: 48, Volume: -1, Effect: EffVibrato, EffectParam: 0x42},
+		Cell{Volume: -1},
+	)
+	m := audio.NewMixerN(silentWave, nil, 1)
+	p := NewPlayer(song, len(m.Ch))
+
+	p.Tick(&m) // Row 0
+	if m.Ch[0].Vibrato == 0 {
+		t.Fatalf("row 0: Vibrato = 0, want nonzero while EffVibrato is active")
+	}
+
+	p.Tick(&m) // Row 1, no effect
+	if m.Ch[0].Vibrato != 0 || m.Ch[0].VibratoRate != 0 {
+		t.Fatalf("row 1: Vibrato = %#x, VibratoRate = %d, want 0/0 once the effect row has passed", m.Ch[0].Vibrato, m.Ch[0].VibratoRate)
+	}
+}
+
+// pairSongOf builds a multi-channel Song from one row of cells, one per
+// tracker channel, to exercise cross-channel pair allocation.
+func pairSongOf(row ...Cell) *Song {
+	return &Song{
+		Orders:    []int{0},
+		Patterns:  []Pattern{{row}},
+		InitBPM:   125,
+		InitSpeed: 1,
+	}
+}
+
+// With as many physical pairs as tracker channels, every channel must
+// land on its own pair: this is a regression test for a bug where
+// pickPhys/cs.phys conflated pair index with raw channel index, folding
+// channels 0 and 1 onto the same pair and leaving others silent.
+func TestChannelsMapToDistinctPairs(t *testing.T) {
+	song := pairSongOf(
+		Cell{Note: 40, Volume: -1},
+		Cell{Note: 50, Volume: -1},
+	)
+	m := audio.NewMixerN(silentWave, nil, 2) // 2 pairs = 4 channels
+	p := NewPlayer(song, len(m.Ch))
+
+	p.Tick(&m)
+	if got, want := m.Ch[0].Note, int32(40)<<16; got != want {
+		t.Errorf("pair 0 (channel 0's note): Note = %#x, want %#x", got, want)
+	}
+	if got, want := m.Ch[2].Note, int32(50)<<16; got != want {
+		t.Errorf("pair 1 (channel 1's note): Note = %#x, want %#x", got, want)
+	}
+}